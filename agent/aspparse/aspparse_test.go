@@ -0,0 +1,182 @@
+package aspparse
+
+import (
+	"os"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatalf("no se pudo leer el fixture %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestCheckBlocksWellFormed(t *testing.T) {
+	src := readFixture(t, "well_formed.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksUnmatchedIf(t *testing.T) {
+	src := readFixture(t, "unmatched_if.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 1 {
+		t.Fatalf("esperaba 1 diagnostic, obtuve %d: %+v", len(diags), diags)
+	}
+	if diags[0].Line != 2 {
+		t.Errorf("esperaba que el If sin cerrar se reportase en la línea 2, obtuve %d", diags[0].Line)
+	}
+}
+
+func TestCheckBlocksStrayEndIf(t *testing.T) {
+	src := readFixture(t, "stray_end_if.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 1 {
+		t.Fatalf("esperaba 1 diagnostic, obtuve %d: %+v", len(diags), diags)
+	}
+	if diags[0].Line != 3 {
+		t.Errorf("esperaba que el End If huérfano se reportase en la línea 3, obtuve %d", diags[0].Line)
+	}
+}
+
+func TestCheckBlocksDoLoopWhile(t *testing.T) {
+	src := readFixture(t, "do_loop_while.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para Do/Loop While, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksDoLoopUntil(t *testing.T) {
+	src := readFixture(t, "do_loop_until.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para Do/Loop Until, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksWhileWend(t *testing.T) {
+	src := readFixture(t, "while_wend.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para While/Wend, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksSelectCase(t *testing.T) {
+	src := readFixture(t, "select_case.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para Select Case/End Select, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksSubFunction(t *testing.T) {
+	src := readFixture(t, "sub_function.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para Sub/Function, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksElseIfChain(t *testing.T) {
+	src := readFixture(t, "elseif_chain.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para la cadena ElseIf, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksExitStatements(t *testing.T) {
+	src := readFixture(t, "exit_statements.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics con Exit For/Do/Sub/Function, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksInlineIfThen(t *testing.T) {
+	src := readFixture(t, "inline_if_then.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 0 {
+		t.Fatalf("esperaba 0 diagnostics para If...Then de una sola línea, obtuve %d: %+v", len(diags), diags)
+	}
+}
+
+func TestCheckBlocksMissingThenDoesNotSkipStrayEndSub(t *testing.T) {
+	src := readFixture(t, "missing_then_stray_end_sub.asp")
+	diags := CheckBlocks(Tokenize(src))
+	if len(diags) != 2 {
+		t.Fatalf("esperaba 2 diagnostics, obtuve %d: %+v", len(diags), diags)
+	}
+	if diags[0].Line != 2 {
+		t.Errorf("esperaba que el If sin Then se reportase en la línea 2, obtuve %d", diags[0].Line)
+	}
+	if diags[1].Line != 3 {
+		t.Errorf("esperaba que el End Sub huérfano se reportase en la línea 3, obtuve %d", diags[1].Line)
+	}
+}
+
+func TestTokenizeSkipsStringsAndComments(t *testing.T) {
+	src := `<%
+msg = "If For Next End If"
+' If For Next comment
+REM If For Next rem comment
+x = 1
+%>`
+	tokens := Tokenize(src)
+
+	var words []string
+	for _, tok := range tokens {
+		words = append(words, tok.Text)
+	}
+
+	want := []string{"msg", "x", "1"}
+	if len(words) != len(want) {
+		t.Fatalf("esperaba tokens %v, obtuve %v", want, words)
+	}
+	for i, w := range want {
+		if words[i] != w {
+			t.Errorf("token %d: esperaba %q, obtuve %q", i, w, words[i])
+		}
+	}
+}
+
+func TestTokenizeHandlesEscapedQuotes(t *testing.T) {
+	src := `<%
+msg = "she said ""hi"""
+Next
+%>`
+	tokens := Tokenize(src)
+	want := []string{"msg", "Next"}
+	if len(tokens) != len(want) {
+		t.Fatalf("esperaba %v, obtuve %+v", want, tokens)
+	}
+	for i, w := range want {
+		if tokens[i].Text != w {
+			t.Errorf("token %d: esperaba %q, obtuve %q", i, w, tokens[i].Text)
+		}
+	}
+}
+
+func TestTokenizeIgnoresHTML(t *testing.T) {
+	src := `<html><body>If For Next are not code here</body></html>
+<%
+For i = 1 To 1
+Next
+%>`
+	tokens := Tokenize(src)
+	var words []string
+	for _, tok := range tokens {
+		words = append(words, tok.Text)
+	}
+	want := []string{"For", "i", "1", "To", "1", "Next"}
+	if len(words) != len(want) {
+		t.Fatalf("esperaba %v, obtuve %v", want, words)
+	}
+}
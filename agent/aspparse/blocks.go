@@ -0,0 +1,209 @@
+package aspparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnostic es un hallazgo del validador de estructura de bloques, con la
+// línea exacta del token que lo provocó.
+type Diagnostic struct {
+	Line    int
+	Message string
+}
+
+// blockKind identifica el tipo de bloque abierto en la pila de CheckBlocks.
+type blockKind string
+
+const (
+	blockIf       blockKind = "If"
+	blockFor      blockKind = "For"
+	blockDo       blockKind = "Do"
+	blockWhile    blockKind = "While"
+	blockSelect   blockKind = "Select Case"
+	blockSub      blockKind = "Sub"
+	blockFunction blockKind = "Function"
+)
+
+type openBlock struct {
+	kind blockKind
+	line int
+}
+
+// CheckBlocks valida que los bloques If/End If, For/Next, Do/Loop,
+// While/Wend, Select Case/End Select y Sub|Function/End Sub|Function estén
+// correctamente anidados en el stream de tokens, y devuelve un Diagnostic
+// por cada apertura sin cierre o cierre sin apertura, con su línea exacta.
+// Entiende ElseIf, Exit For/Do/Sub/Function y el If ... Then ... de una
+// sola línea (que no requiere End If).
+func CheckBlocks(tokens []Token) []Diagnostic {
+	var diags []Diagnostic
+	var stack []openBlock
+
+	pop := func(expect blockKind, closer string, line int) {
+		if len(stack) == 0 || stack[len(stack)-1].kind != expect {
+			diags = append(diags, Diagnostic{
+				Line:    line,
+				Message: fmt.Sprintf("%s sin apertura correspondiente", closer),
+			})
+			return
+		}
+		stack = stack[:len(stack)-1]
+	}
+
+	// lastKeyword recuerda si el token anterior fue "do" o "loop", para que
+	// el "While"/"Until" que le sigue inmediatamente (la condición de
+	// "Do While ..."/"Loop While ..."/"Loop Until ...") no se confunda con
+	// la apertura de un bloque While propio.
+	lastKeyword := ""
+
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		word := strings.ToLower(tok.Text)
+		prevKeyword := lastKeyword
+		lastKeyword = ""
+
+		switch word {
+		case "exit":
+			// Exit For/Do/Sub/Function/Property no abre ni cierra nada.
+			if i+1 < len(tokens) {
+				i++
+			}
+			continue
+
+		case "if":
+			thenIdx := findKeywordOnSameStatement(tokens, i, "then")
+			if thenIdx == -1 {
+				// "If" sin "Then" visible cerca (p.ej. recortado, mal escrito,
+				// o con otro bloque de por medio): lo señalamos en vez de
+				// emparejarlo con un "Then" arbitrario más adelante.
+				diags = append(diags, Diagnostic{
+					Line:    tok.Line,
+					Message: "If sin Then correspondiente en la misma sentencia",
+				})
+				continue
+			}
+			if isInlineThen(tokens, thenIdx) {
+				// If x Then foo  → sentencia de una línea, no abre bloque.
+				i = thenIdx
+				continue
+			}
+			stack = append(stack, openBlock{blockIf, tok.Line})
+			i = thenIdx
+
+		case "for":
+			stack = append(stack, openBlock{blockFor, tok.Line})
+
+		case "next":
+			pop(blockFor, "Next", tok.Line)
+
+		case "do":
+			stack = append(stack, openBlock{blockDo, tok.Line})
+			lastKeyword = "do"
+
+		case "loop":
+			pop(blockDo, "Loop", tok.Line)
+			lastKeyword = "loop"
+
+		case "while":
+			if prevKeyword == "do" || prevKeyword == "loop" {
+				// "Do While ..." / "Loop While ..." → condición, no bloque propio.
+				continue
+			}
+			stack = append(stack, openBlock{blockWhile, tok.Line})
+
+		case "until":
+			// "Do Until ..." / "Loop Until ..." → condición, no bloque propio.
+
+		case "wend":
+			pop(blockWhile, "Wend", tok.Line)
+
+		case "select":
+			if i+1 < len(tokens) && strings.EqualFold(tokens[i+1].Text, "case") {
+				stack = append(stack, openBlock{blockSelect, tok.Line})
+				i++
+			}
+
+		case "sub":
+			stack = append(stack, openBlock{blockSub, tok.Line})
+
+		case "function":
+			stack = append(stack, openBlock{blockFunction, tok.Line})
+
+		case "end":
+			if i+1 < len(tokens) {
+				switch strings.ToLower(tokens[i+1].Text) {
+				case "if":
+					pop(blockIf, "End If", tok.Line)
+					i++
+				case "select":
+					pop(blockSelect, "End Select", tok.Line)
+					i++
+				case "sub":
+					pop(blockSub, "End Sub", tok.Line)
+					i++
+				case "function":
+					pop(blockFunction, "End Function", tok.Line)
+					i++
+				}
+			}
+		}
+	}
+
+	for _, open := range stack {
+		diags = append(diags, Diagnostic{
+			Line:    open.line,
+			Message: fmt.Sprintf("%s abierto aquí nunca se cierra", open.kind),
+		})
+	}
+
+	return diags
+}
+
+// findKeywordOnSameStatement busca, a partir de from, el primer token cuyo
+// texto (sin distinguir mayúsculas) coincida con keyword, sin cruzar más de
+// una línea razonable de distancia (una sentencia If puede partirse con " _"
+// pero no continúa indefinidamente) y sin cruzar el inicio de otra sentencia
+// de bloque. Devuelve -1 si no se encuentra dentro de esos límites.
+func findKeywordOnSameStatement(tokens []Token, from int, keyword string) int {
+	startLine := tokens[from].Line
+	for i := from + 1; i < len(tokens); i++ {
+		if tokens[i].Line > startLine+1 {
+			return -1
+		}
+		if strings.EqualFold(tokens[i].Text, keyword) {
+			return i
+		}
+		if isBlockStatementKeyword(tokens[i].Text) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// isBlockStatementKeyword indica si word abre o cierra alguno de los
+// bloques que CheckBlocks reconoce; se usa para no dejar que la búsqueda
+// de un "Then" cruce por encima de otra sentencia de bloque.
+func isBlockStatementKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "if", "for", "next", "do", "loop", "while", "wend", "select", "sub", "function", "end":
+		return true
+	}
+	return false
+}
+
+// isInlineThen decide si el "Then" en thenIdx cierra una sentencia If de una
+// sola línea (hay más tokens en la misma línea física) o si abre un bloque
+// If que se cerrará con un End If en una línea posterior.
+func isInlineThen(tokens []Token, thenIdx int) bool {
+	if thenIdx+1 >= len(tokens) {
+		return false
+	}
+	next := tokens[thenIdx+1]
+	if next.Line != tokens[thenIdx].Line {
+		return false
+	}
+	// "Then" seguido de "Else"/"ElseIf" en la misma línea sigue siendo un
+	// bloque (p.ej. "If x Then Else" no es idiomático, pero no es inline).
+	return !strings.EqualFold(next.Text, "else") && !strings.EqualFold(next.Text, "elseif")
+}
@@ -0,0 +1,141 @@
+// Package aspparse implementa un tokenizador ligero para páginas ASP
+// clásico (VBScript embebido en HTML) y un validador de estructura de
+// bloques construido sobre él. No es un parser VBScript completo: solo
+// reconoce las palabras clave necesarias para comprobar que los bloques
+// (If/For/Do/While/Select/Sub/Function) están bien anidados, ignorando
+// el HTML, los literales de cadena y los comentarios que los rodean.
+package aspparse
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Token es una palabra de VBScript dentro de un bloque de script (<% %>),
+// junto con la línea en la que aparece dentro del archivo original.
+type Token struct {
+	Text string
+	Line int
+}
+
+// Tokenize recorre el contenido de un archivo .asp y devuelve únicamente
+// las palabras (identificadores, keywords y literales numéricos) que aparecen dentro de bloques de
+// script, saltándose el HTML, los literales de cadena ("..." con comillas
+// dobles escapadas como "") y los comentarios (' y REM).
+func Tokenize(src string) []Token {
+	var tokens []Token
+	line := 1
+	inScript := false
+
+	var word strings.Builder
+	flushWord := func(wordLine int) {
+		if word.Len() > 0 {
+			tokens = append(tokens, Token{Text: word.String(), Line: wordLine})
+			word.Reset()
+		}
+	}
+
+	runes := []rune(src)
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		c := runes[i]
+
+		if c == '\n' {
+			flushWord(line)
+			line++
+			continue
+		}
+
+		if !inScript {
+			if c == '<' && i+1 < n && runes[i+1] == '%' {
+				inScript = true
+				i++
+			}
+			continue
+		}
+
+		// Dentro de un bloque <% ... %>
+		if c == '%' && i+1 < n && runes[i+1] == '>' {
+			flushWord(line)
+			inScript = false
+			i++
+			continue
+		}
+
+		if c == '"' {
+			flushWord(line)
+			i++
+			for i < n {
+				if runes[i] == '\n' {
+					line++
+					i++
+					continue
+				}
+				if runes[i] == '"' {
+					// "" dentro de una cadena es una comilla escapada
+					if i+1 < n && runes[i+1] == '"' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+			i--
+			continue
+		}
+
+		if c == '\'' {
+			flushWord(line)
+			for i < n && runes[i] != '\n' {
+				i++
+			}
+			i--
+			continue
+		}
+
+		if isWordRune(c) {
+			// REM es un comentario de línea completa solo si aparece como
+			// palabra independiente (no dentro de otro identificador).
+			if (c == 'r' || c == 'R') && word.Len() == 0 && isRemComment(runes, i) {
+				for i < n && runes[i] != '\n' {
+					i++
+				}
+				i--
+				continue
+			}
+			word.WriteRune(c)
+			continue
+		}
+
+		flushWord(line)
+	}
+	flushWord(line)
+
+	return tokens
+}
+
+// isWordRune indica si c puede formar parte de un identificador/keyword de
+// VBScript (letras, dígitos y guion bajo).
+func isWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// isRemComment comprueba si, a partir de la posición i, el texto forma la
+// palabra completa "REM" (sin caracteres de identificador antes o después).
+func isRemComment(runes []rune, i int) bool {
+	word := "rem"
+	n := len(runes)
+	if i+len(word) > n {
+		return false
+	}
+	for j, want := range word {
+		got := unicode.ToLower(runes[i+j])
+		if got != want {
+			return false
+		}
+	}
+	end := i + len(word)
+	return end == n || !isWordRune(runes[end])
+}
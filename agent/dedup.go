@@ -0,0 +1,109 @@
+// ================================================
+// DEDUPLICACIÓN DE CAMBIOS – .gitignore, hash y debounce por ruta
+// ================================================
+package main
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// loadGitignore compila el .gitignore del repo, si existe. Un repo sin
+// .gitignore es válido: en ese caso no se filtra ningún archivo.
+func loadGitignore(repo string) *ignore.GitIgnore {
+	gi, err := ignore.CompileIgnoreFile(filepath.Join(repo, ".gitignore"))
+	if err != nil {
+		if verbose {
+			log.Printf("Sin .gitignore aplicable en %s: %v", repo, err)
+		}
+		return nil
+	}
+	return gi
+}
+
+// isGitignored comprueba si path (relativo o absoluto) está cubierto por el
+// .gitignore del repo.
+func isGitignored(gi *ignore.GitIgnore, repo, path string) bool {
+	if gi == nil {
+		return false
+	}
+	absRepo, err := filepath.Abs(repo)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRepo, absPath)
+	if err != nil {
+		return false
+	}
+	return gi.MatchesPath(filepath.ToSlash(rel))
+}
+
+// isNoopChange compara el hash de blob git del contenido actual de path con
+// el del blob ya comprometido en HEAD para esa ruta (git hash-object /
+// git rev-parse HEAD:<path>). Si coinciden, el archivo se reescribió con el
+// mismo contenido y no merece un commit.
+func isNoopChange(repo, path string) bool {
+	rel, err := filepath.Rel(repo, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	current, err := runGit(repo, "hash-object", path)
+	if err != nil {
+		// Archivo ilegible/borrado: no lo tratamos como no-op, que lo
+		// decida el resto del pipeline (p.ej. git add detectará el borrado).
+		return false
+	}
+
+	committed, err := runGit(repo, "rev-parse", "HEAD:"+rel)
+	if err != nil {
+		// Archivo nuevo, o el repo no tiene aún un HEAD con esa ruta.
+		return false
+	}
+
+	return strings.TrimSpace(current) == strings.TrimSpace(committed)
+}
+
+// pathDebouncer coalesce eventos repetidos sobre la misma ruta dentro de una
+// ventana corta (los editores suelen emitir varios Write/Create/Rename por
+// cada guardado) antes de tocar state.changed.
+type pathDebouncer struct {
+	window time.Duration
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+func newPathDebouncer(window time.Duration) *pathDebouncer {
+	return &pathDebouncer{
+		window: window,
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// fire reprograma el temporizador de path: si ya había un evento pendiente
+// para esa misma ruta, lo reemplaza en lugar de apilar otro. onSettle se
+// invoca una única vez, transcurrida la ventana sin nuevos eventos.
+func (d *pathDebouncer) fire(path string, onSettle func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t, ok := d.timers[path]; ok {
+		t.Stop()
+	}
+	d.timers[path] = time.AfterFunc(d.window, func() {
+		d.mu.Lock()
+		delete(d.timers, path)
+		d.mu.Unlock()
+		onSettle()
+	})
+}
@@ -0,0 +1,100 @@
+// ================================================
+// ESTRATEGIA DE PUSH – rebase, backoff y conflictos
+// ================================================
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// pushRetries y pushBackoffBase controlan el reintento con backoff
+// exponencial de pushWithRetry. maxRebaseRetries acota por separado los
+// ciclos de rebase-y-reintento: cada uno de ellos se concede gratis (no
+// gasta presupuesto de pushRetries) porque tras un rebase limpio el push
+// debería ser fast-forward, pero si el remoto sigue avanzando sin parar no
+// queremos bloquear indefinidamente el goroutine principal del agente.
+const (
+	pushRetries      = 3
+	pushBackoffBase  = 2 * time.Second
+	maxRebaseRetries = 5
+)
+
+// pushWithRetry empuja repo a remote. Si el push se rechaza por
+// non-fast-forward (otro escritor empujó entre medias), sincroniza con
+// git pull --rebase --autostash y reintenta; si el rebase tiene conflictos,
+// lo aborta y devuelve un error estructurado sin tocar el commit local, que
+// queda pendiente de subir en el siguiente intento. Los fallos transitorios
+// (red, remoto caído) se reintentan con backoff exponencial. Si el remoto
+// sigue avanzando y obliga a más de maxRebaseRetries rebases seguidos, se
+// desiste y se devuelve un error en vez de reintentar sin límite.
+func pushWithRetry(repo, remote string) error {
+	backoff := pushBackoffBase
+	var lastErr error
+	rebaseAttempts := 0
+
+	for attempt := 1; attempt <= pushRetries; attempt++ {
+		out, err := runGit(repo, "push", remote)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("git push falló: %v -> %s", err, out)
+
+		if isNonFastForward(out) {
+			rebaseAttempts++
+			if rebaseAttempts > maxRebaseRetries {
+				return fmt.Errorf(
+					"%d rebases consecutivos contra %s y el remoto sigue avanzando, desisto: %w",
+					maxRebaseRetries, remote, lastErr,
+				)
+			}
+			log.Printf("Push a %s rechazado (non-fast-forward), sincronizando con pull --rebase --autostash", remote)
+			if rebaseErr := rebaseOnto(repo, remote); rebaseErr != nil {
+				return rebaseErr
+			}
+			// El rebase terminó sin conflictos: el push ahora debería ser
+			// fast-forward, así que reintentamos de inmediato sin gastar
+			// un intento del backoff ni tratarlo como fallo transitorio.
+			attempt--
+			continue
+		}
+
+		if attempt == pushRetries {
+			break
+		}
+
+		log.Printf("Reintentando push (intento %d/%d) en %s: %v", attempt, pushRetries, backoff, lastErr)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return lastErr
+}
+
+// rebaseOnto sincroniza repo con remote vía pull --rebase --autostash. Si el
+// rebase entra en conflicto, lo aborta (dejando el commit local intacto en
+// su rama, sin sincronizar) y devuelve un error pensado para que un humano
+// lo resuelva manualmente.
+func rebaseOnto(repo, remote string) error {
+	out, err := runGit(repo, "pull", "--rebase", "--autostash", remote)
+	if err == nil {
+		return nil
+	}
+
+	_, _ = runGit(repo, "rebase", "--abort")
+	return fmt.Errorf(
+		"conflicto al hacer rebase sobre %s; el commit local se mantiene sin subir, resuélvelo manualmente: %v -> %s",
+		remote, err, out,
+	)
+}
+
+// isNonFastForward detecta si la salida de git push corresponde a un
+// rechazo por non-fast-forward (el remoto avanzó desde el último fetch).
+func isNonFastForward(output string) bool {
+	l := strings.ToLower(output)
+	return strings.Contains(l, "non-fast-forward") ||
+		strings.Contains(l, "fetch first") ||
+		strings.Contains(l, "[rejected]")
+}
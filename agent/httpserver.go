@@ -0,0 +1,80 @@
+// ================================================
+// SERVIDOR HTTP DE CONTROL – healthz, metrics, pending y flush
+// ================================================
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// startHTTPServer arranca, en segundo plano, un servidor HTTP opcional de
+// estado y control del agente. flushCh se usa para pedir un commit
+// inmediato sin que el handler HTTP toque directamente state ni los
+// timers del bucle principal, que siguen siendo su único dueño.
+func startHTTPServer(addr string, state *agentState, flushCh chan<- string) *http.Server {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/pending", func(w http.ResponseWriter, r *http.Request) {
+		state.mu.Lock()
+		files := make([]string, 0, len(state.changed))
+		for f := range state.changed {
+			files = append(files, filepath.Base(f))
+		}
+		state.mu.Unlock()
+		sort.Strings(files)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"pending": files,
+			"count":   len(files),
+		})
+	})
+
+	mux.HandleFunc("/flush", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "método no permitido", http.StatusMethodNotAllowed)
+			return
+		}
+		select {
+		case flushCh <- "flush solicitado por HTTP":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte("commit encolado"))
+		default:
+			http.Error(w, "ya hay un flush en curso", http.StatusTooManyRequests)
+		}
+	})
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		log.Printf("Servidor HTTP de control escuchando en %s", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Servidor HTTP de control detenido: %v", err)
+		}
+	}()
+
+	return srv
+}
+
+// shutdownHTTPServer cierra srv ordenadamente, usando ctx como plazo máximo
+// de apagado; se ignora el error porque el agente ya está terminando de
+// todos modos.
+func shutdownHTTPServer(ctx context.Context, srv *http.Server) {
+	if srv == nil {
+		return
+	}
+	_ = srv.Shutdown(ctx)
+}
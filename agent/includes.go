@@ -0,0 +1,249 @@
+// ================================================
+// GRAFO DE INCLUDES – resolución recursiva de <!--#include-->
+// ================================================
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// vrootFlag implementa flag.Value para aceptar varios --vroot repetidos,
+// cada uno con la forma "prefijo_virtual=directorio_fisico", p.ej.
+// "--vroot /=./wwwroot --vroot /shared=./wwwroot/shared".
+type vrootFlag map[string]string
+
+func (v vrootFlag) String() string {
+	parts := make([]string, 0, len(v))
+	for prefix, dir := range v {
+		parts = append(parts, fmt.Sprintf("%s=%s", prefix, dir))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (v vrootFlag) Set(value string) error {
+	prefix, dir, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("formato de --vroot inválido, se esperaba prefijo=directorio: %q", value)
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	v[prefix] = dir
+	return nil
+}
+
+// resolveVirtual resuelve una ruta "virtual=" al estilo IIS: busca el
+// vroot configurado con el prefijo más largo que encaje y une el resto de
+// la ruta a su directorio físico. Sin vroots configurados, la virtual se
+// resuelve relativa a repoDir (la raíz del sitio por defecto).
+func resolveVirtual(rawPath string, vroots vrootFlag, repoDir string) string {
+	if !strings.HasPrefix(rawPath, "/") {
+		rawPath = "/" + rawPath
+	}
+
+	bestPrefix := ""
+	for prefix := range vroots {
+		if strings.HasPrefix(rawPath, prefix) && len(prefix) > len(bestPrefix) {
+			bestPrefix = prefix
+		}
+	}
+	if bestPrefix != "" {
+		rel := strings.TrimPrefix(rawPath, bestPrefix)
+		return filepath.Join(vroots[bestPrefix], rel)
+	}
+
+	return filepath.Join(repoDir, strings.TrimPrefix(rawPath, "/"))
+}
+
+// includeRef es una referencia <!--#include--> encontrada en un archivo.
+type includeRef struct {
+	Kind string // "file" o "virtual"
+	Path string // ruta tal y como aparece en el include
+	Line int
+}
+
+// parseIncludeRefs extrae todas las referencias #include de un archivo.
+func parseIncludeRefs(file string) ([]includeRef, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	text := string(content)
+
+	var refs []includeRef
+	for _, m := range includeRegex.FindAllStringSubmatchIndex(text, -1) {
+		refs = append(refs, includeRef{
+			Kind: text[m[2]:m[3]],
+			Path: text[m[4]:m[5]],
+			Line: lineAt(text, m[0]),
+		})
+	}
+	return refs, nil
+}
+
+// resolveIncludeRef resuelve una includeRef a una ruta física, relativa al
+// archivo que la contiene (para "file=") o al vroot configurado (para
+// "virtual=").
+func resolveIncludeRef(includer string, ref includeRef, vroots vrootFlag, repoDir string) string {
+	if ref.Kind == "virtual" {
+		return resolveVirtual(ref.Path, vroots, repoDir)
+	}
+	return filepath.Join(filepath.Dir(includer), ref.Path)
+}
+
+// IncludeEdge es una arista del grafo de includes: from incluye to.
+type IncludeEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Line int    `json:"line"`
+}
+
+// IncludeGraph es el grafo completo de includes de un conjunto de archivos,
+// pensado para --print-includes (inspección con tooling externo).
+type IncludeGraph struct {
+	Edges []IncludeEdge `json:"edges"`
+}
+
+// BuildIncludeGraph construye el grafo de includes de files siguiendo
+// recursivamente todos los includes que existan en disco, estén o no en el
+// propio conjunto de entrada; para eso, camina exhaustivamente desde cada
+// archivo usando walkIncludes con un visited propio por archivo raíz, igual
+// que el validador, pero acumulando todas las aristas en un único grafo.
+func BuildIncludeGraph(files []string, vroots vrootFlag, repoDir string) *IncludeGraph {
+	graph := &IncludeGraph{}
+	seenEdge := make(map[string]bool)
+
+	for _, file := range files {
+		walkIncludes(file, vroots, repoDir, nil, make(map[string]bool), make(map[string]int), func(from string, ref includeRef, to string) {
+			key := from + "->" + to
+			if seenEdge[key] {
+				return
+			}
+			seenEdge[key] = true
+			graph.Edges = append(graph.Edges, IncludeEdge{From: from, To: to, Line: ref.Line})
+		})
+	}
+
+	return graph
+}
+
+// DOT serializa el grafo en formato Graphviz DOT.
+func (g *IncludeGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph includes {\n")
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// JSON serializa el grafo como JSON indentado.
+func (g *IncludeGraph) JSON() (string, error) {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// walkIncludes recorre recursivamente los includes de file en profundidad,
+// invocando onEdge por cada referencia encontrada (exista o no el destino).
+// stack es la pila de archivos en la rama actual (para detectar ciclos);
+// visited y counts acumulan, por árbol de inclusión completo (no por
+// llamada), qué archivos ya se visitaron y cuántas veces se ha referenciado
+// cada uno, para que un archivo incluido dos veces desde ramas distintas de
+// la misma página (no solo dos veces desde el mismo archivo) se reporte
+// como duplicado. Devuelve diagnostics de ciclos e includes duplicados.
+func walkIncludes(file string, vroots vrootFlag, repoDir string, stack []string, visited map[string]bool, counts map[string]int, onEdge func(from string, ref includeRef, to string)) []Diagnostic {
+	var diags []Diagnostic
+
+	refs, err := parseIncludeRefs(file)
+	if err != nil {
+		return diags
+	}
+
+	for _, ref := range refs {
+		target := resolveIncludeRef(file, ref, vroots, repoDir)
+		target = filepath.Clean(target)
+
+		if onEdge != nil {
+			onEdge(file, ref, target)
+		}
+
+		if _, err := os.Stat(target); err != nil {
+			diags = append(diags, Diagnostic{
+				File: file, Line: ref.Line, Rule: "include", Severity: SeverityError,
+				Message: fmt.Sprintf("archivo no encontrado: %s", ref.Path),
+			})
+			continue
+		}
+
+		counts[target]++
+		if counts[target] > 1 {
+			diags = append(diags, Diagnostic{
+				File: file, Line: ref.Line, Rule: "include-duplicate", Severity: SeverityWarn,
+				Message: fmt.Sprintf("%s se incluye más de una vez en esta página", ref.Path),
+			})
+		}
+
+		if cyclePos := indexOf(stack, target); cyclePos != -1 {
+			cycle := append(append([]string{}, stack[cyclePos:]...), target)
+			diags = append(diags, Diagnostic{
+				File: file, Line: ref.Line, Rule: "include-cycle", Severity: SeverityError,
+				Message: fmt.Sprintf("ciclo de includes detectado: %s", strings.Join(cycle, " -> ")),
+			})
+			continue
+		}
+
+		if visited[target] {
+			// Ya se expandió en otra rama de este mismo árbol; no hay ciclo,
+			// pero tampoco merece la pena volver a recorrerlo.
+			continue
+		}
+		visited[target] = true
+
+		diags = append(diags, walkIncludes(target, vroots, repoDir, append(stack, target), visited, counts, onEdge)...)
+	}
+
+	return diags
+}
+
+func indexOf(haystack []string, needle string) int {
+	for i, s := range haystack {
+		if s == needle {
+			return i
+		}
+	}
+	return -1
+}
+
+// collectASPFiles recorre root y devuelve todos los archivos cuya extensión
+// esté en exts, en el mismo espíritu que addRecursive pero sin necesitar un
+// fsnotify.Watcher (usado por --print-includes).
+func collectASPFiles(root string, exts map[string]bool) ([]string, error) {
+	var files []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if isSkippedDir(filepath.Base(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchesExtension(path, exts) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
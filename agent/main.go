@@ -2,9 +2,11 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -15,15 +17,30 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	ignore "github.com/sabhiram/go-gitignore"
 )
 
 var (
-	watchDir   string
-	repoDir    string
-	interval   int
-	maxWait    int
-	extensions string
-	verbose    bool
+	watchDir          string
+	repoDir           string
+	interval          int
+	maxWait           int
+	extensions        string
+	verbose           bool
+	validatorsConfig  string
+	reportPath        string
+	vroots            = make(vrootFlag)
+	printIncludes     string
+	coalesceMs        int
+	reconcileInterval time.Duration
+	signCommit        bool
+	signOff           bool
+	authorName        string
+	authorEmail       string
+	committerName     string
+	committerEmail    string
+	remoteName        string
+	httpAddr          string
 )
 
 func init() {
@@ -33,6 +50,20 @@ func init() {
 	flag.IntVar(&maxWait, "max-wait", 900, "Tiempo máximo en segundos desde el primer cambio para forzar un commit (por defecto 600s)")
 	flag.StringVar(&extensions, "ext", ".asp", "Extensiones a vigilar separadas por coma (por defecto .asp)")
 	flag.BoolVar(&verbose, "verbose", false, "Modo verbose para más logs")
+	flag.StringVar(&validatorsConfig, "validators-config", defaultPipelineConfigPath, "Archivo YAML con la configuración del pipeline de validators")
+	flag.StringVar(&reportPath, "report", "precommit-report.json", "Ruta del reporte estructurado generado cuando un commit se bloquea")
+	flag.Var(&vroots, "vroot", "Mapeo virtual=física para <!--#include virtual=\"...\"--> (repetible), p.ej. /=./wwwroot")
+	flag.StringVar(&printIncludes, "print-includes", "", "Imprime el grafo de includes (dot|json) de los archivos vigilados y termina")
+	flag.IntVar(&coalesceMs, "coalesce-ms", 250, "Ventana en milisegundos para agrupar eventos repetidos sobre la misma ruta")
+	flag.DurationVar(&reconcileInterval, "watch-reconcile-interval", 30*time.Second, "Intervalo de reconciliación del árbol vigilado y de polling de directorios sin inotify")
+	flag.BoolVar(&signCommit, "sign", false, "Firma los commits con GPG (git commit -S)")
+	flag.BoolVar(&signOff, "sign-off", false, "Añade Signed-off-by al mensaje de commit (git commit --signoff)")
+	flag.StringVar(&authorName, "author-name", "", "Nombre de autor a usar en los commits (por defecto, el configurado en git)")
+	flag.StringVar(&authorEmail, "author-email", "", "Email de autor a usar en los commits")
+	flag.StringVar(&committerName, "committer-name", "", "Nombre de committer a usar en los commits")
+	flag.StringVar(&committerEmail, "committer-email", "", "Email de committer a usar en los commits")
+	flag.StringVar(&remoteName, "remote", "origin", "Remoto git al que hacer push")
+	flag.StringVar(&httpAddr, "http", "", "Dirección donde escuchar peticiones de estado/control (p.ej. :7777); vacío desactiva el servidor")
 }
 
 func main() {
@@ -46,6 +77,37 @@ func main() {
 	// Parsear extensiones
 	exts := parseExtensions(extensions)
 
+	if printIncludes != "" {
+		files, err := collectASPFiles(watchDir, exts)
+		if err != nil {
+			log.Fatalf("Error al recorrer %s: %v", watchDir, err)
+		}
+		graph := BuildIncludeGraph(files, vroots, repoDir)
+		switch printIncludes {
+		case "dot":
+			fmt.Print(graph.DOT())
+		case "json":
+			out, err := graph.JSON()
+			if err != nil {
+				log.Fatalf("Error al serializar el grafo de includes: %v", err)
+			}
+			fmt.Println(out)
+		default:
+			log.Fatalf("--print-includes debe ser \"dot\" o \"json\", no %q", printIncludes)
+		}
+		return
+	}
+
+	// Cargar config del pipeline de pre-commit (o los defaults si no existe)
+	cfgPath := validatorsConfig
+	if cfgPath == defaultPipelineConfigPath {
+		cfgPath = filepath.Join(repoDir, cfgPath)
+	}
+	pipelineCfg, err := LoadPipelineConfig(cfgPath)
+	if err != nil {
+		log.Fatalf("Error al cargar %s: %v", cfgPath, err)
+	}
+
 	log.Printf("Agente Git iniciado")
 	log.Printf("Vigilando: %s", watchDir)
 	log.Printf("Repositorio: %s", repoDir)
@@ -62,6 +124,8 @@ func main() {
 	state := &agentState{
 		changed:    make(map[string]struct{}),
 		extensions: exts,
+		gitignore:  loadGitignore(repoDir),
+		debouncer:  newPathDebouncer(time.Duration(coalesceMs) * time.Millisecond),
 	}
 
 	// Timers
@@ -71,6 +135,22 @@ func main() {
 	var maxTimerCh <-chan time.Time
 	var firstChangeTime time.Time
 
+	// settled recibe un aviso cada vez que el debouncer de una ruta se
+	// asienta. commitTimer/maxTimer/maxTimerCh/firstChangeTime solo se tocan
+	// desde el goroutine del select de abajo: pathDebouncer.fire dispara
+	// onSettle en su propio goroutine de time.AfterFunc, así que no puede
+	// llamar a scheduleCommit directamente sin introducir una carrera de
+	// datos entre guardados concurrentes. Es un buffer de 1 tipo "timbre":
+	// si ya hay un aviso pendiente sin consumir, no hace falta apilar otro,
+	// scheduleCommit ya relee state.changed cuando se procese.
+	settled := make(chan struct{}, 1)
+	notifySettled := func() {
+		select {
+		case settled <- struct{}{}:
+		default:
+		}
+	}
+
 	scheduleCommit := func() {
 		if !commitTimer.Stop() {
 			select {
@@ -98,12 +178,24 @@ func main() {
 		}
 	}
 
-	// Agregar watchers recursivamente
-	if err := addRecursive(watcher, watchDir); err != nil {
-		log.Fatalf("Error al añadir watchers: %v", err)
+	// Agregar watchers recursivamente (los fallos quedan registrados para
+	// reintento y polling en vez de descartarse)
+	wm := newWatchManager(watcher, watchDir, reconcileInterval)
+	if err := wm.addRecursive(watchDir); err != nil {
+		log.Fatalf("Error al recorrer %s: %v", watchDir, err)
 	}
 
 	done := make(chan struct{})
+	maintenanceStop := make(chan struct{})
+	go wm.maintain(maintenanceStop)
+
+	var httpSrv *http.Server
+	var flushCh chan string
+	if httpAddr != "" {
+		flushCh = make(chan string, 1)
+		httpSrv = startHTTPServer(httpAddr, state, flushCh)
+	}
+
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
 
@@ -114,7 +206,13 @@ func main() {
 				if !ok {
 					return
 				}
-				handleEvent(event, watcher, state, scheduleCommit)
+				handleEvent(event, wm, state, notifySettled)
+
+			case event, ok := <-wm.Events():
+				if !ok {
+					return
+				}
+				handleEvent(event, wm, state, notifySettled)
 
 			case err, ok := <-watcher.Errors:
 				if !ok {
@@ -122,8 +220,15 @@ func main() {
 				}
 				log.Println("Watcher error:", err)
 
+			case <-settled:
+				scheduleCommit()
+
 			case <-commitTimer.C:
-				performCommit(state, &maxTimer, &maxTimerCh, "debounce completado")
+				performCommit(state, &maxTimer, &maxTimerCh, pipelineCfg, "debounce completado")
+
+			case reason := <-flushCh:
+				commitTimer.Stop()
+				performCommit(state, &maxTimer, &maxTimerCh, pipelineCfg, reason)
 
 			case <-maxTimerCh:
 				if maxTimer != nil {
@@ -131,7 +236,7 @@ func main() {
 					maxTimerCh = nil
 				}
 				elapsed := time.Since(firstChangeTime)
-				performCommit(state, &maxTimer, &maxTimerCh,
+				performCommit(state, &maxTimer, &maxTimerCh, pipelineCfg,
 					fmt.Sprintf("max-wait alcanzado (%.0fs)", elapsed.Seconds()))
 
 			case s := <-sig:
@@ -140,7 +245,13 @@ func main() {
 				if maxTimer != nil {
 					maxTimer.Stop()
 				}
-				performCommit(state, &maxTimer, &maxTimerCh, "flush on exit")
+				performCommit(state, &maxTimer, &maxTimerCh, pipelineCfg, "flush on exit")
+				close(maintenanceStop)
+				if httpSrv != nil {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					shutdownHTTPServer(ctx, httpSrv)
+					cancel()
+				}
 				close(done)
 				return
 			}
@@ -155,14 +266,21 @@ type agentState struct {
 	mu         sync.Mutex
 	changed    map[string]struct{}
 	extensions map[string]bool
+	gitignore  *ignore.GitIgnore
+	debouncer  *pathDebouncer
 }
 
-func handleEvent(event fsnotify.Event, watcher *fsnotify.Watcher, state *agentState, scheduleCommit func()) {
+// handleEvent procesa un evento de fsnotify/watchManager. notifySettled se
+// invoca una vez asentado el debounce de una ruta; corre en el goroutine de
+// time.AfterFunc del debouncer, así que se limita a avisar por canal en vez
+// de tocar directamente los timers de commit (eso lo hace el select
+// principal en main, el único goroutine que los posee).
+func handleEvent(event fsnotify.Event, wm *watchManager, state *agentState, notifySettled func()) {
 	// Directorios nuevos
 	if event.Op&fsnotify.Create == fsnotify.Create {
 		info, err := os.Stat(event.Name)
 		if err == nil && info.IsDir() {
-			_ = addRecursive(watcher, event.Name)
+			_ = wm.addRecursive(event.Name)
 			if verbose {
 				log.Printf("Nuevo directorio añadido al watcher: %s", event.Name)
 			}
@@ -170,27 +288,48 @@ func handleEvent(event fsnotify.Event, watcher *fsnotify.Watcher, state *agentSt
 		}
 	}
 
+	// Un Remove/Rename cuyo origen sigue existiendo en disco es el patrón
+	// típico de un "atomic save" de editor (escribir a un temporal y
+	// renombrar sobre el original), que puede dejar huérfano el watch de
+	// inotify: se reintenta añadirlo.
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		wm.reAddAfterAtomicSave(event.Name)
+	}
+
 	// Cambios en archivos relevantes
 	if matchesExtension(event.Name, state.extensions) &&
 		(event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename)) != 0 {
-		state.mu.Lock()
-		state.changed[event.Name] = struct{}{}
-		count := len(state.changed)
-		state.mu.Unlock()
+		if isGitignored(state.gitignore, repoDir, event.Name) {
+			if verbose {
+				log.Printf("Ignorado por .gitignore: %s", event.Name)
+			}
+			return
+		}
 
-		log.Printf("Cambio detectado [%d]: %s", count, filepath.Base(event.Name))
-		scheduleCommit()
+		// Los editores suelen disparar varios eventos por guardado; se
+		// agrupan antes de tocar state.changed.
+		state.debouncer.fire(event.Name, func() {
+			state.mu.Lock()
+			state.changed[event.Name] = struct{}{}
+			count := len(state.changed)
+			metricPendingChanges.Set(float64(count))
+			state.mu.Unlock()
+
+			log.Printf("Cambio detectado [%d]: %s", count, filepath.Base(event.Name))
+			notifySettled()
+		})
 	}
 }
 
-func performCommit(state *agentState, maxTimer **time.Timer, maxTimerCh *<-chan time.Time, reason string) {
+func performCommit(state *agentState, maxTimer **time.Timer, maxTimerCh *<-chan time.Time, pipelineCfg *PipelineConfig, reason string) {
 	state.mu.Lock()
 	files := make([]string, 0, len(state.changed))
 	for f := range state.changed {
 		files = append(files, f)
 	}
-	state.changed = make(map[string]struct{})
+	count := len(state.changed)
 	state.mu.Unlock()
+	metricPendingChanges.Set(float64(count))
 
 	// Limpiar max timer
 	if *maxTimer != nil {
@@ -206,13 +345,71 @@ func performCommit(state *agentState, maxTimer **time.Timer, maxTimerCh *<-chan
 		return
 	}
 
+	// Descartar archivos cuyo contenido actual coincide con el blob ya
+	// comprometido en HEAD (reescrituras sin cambio real, p.ej. un guardado
+	// idéntico desde el editor).
+	real := files[:0]
+	for _, f := range files {
+		if isNoopChange(repoDir, f) {
+			if verbose {
+				log.Printf("Sin cambios reales en %s, se omite", filepath.Base(f))
+			}
+			state.mu.Lock()
+			delete(state.changed, f)
+			metricPendingChanges.Set(float64(len(state.changed)))
+			state.mu.Unlock()
+			continue
+		}
+		real = append(real, f)
+	}
+	files = real
+
+	if len(files) == 0 {
+		if verbose {
+			log.Printf("%s: todos los cambios eran no-op", reason)
+		}
+		return
+	}
+
 	log.Printf("Commit iniciado (%s): %d archivo(s)", reason, len(files))
-	start := time.Now()
 
+	result, err := RunPipeline(pipelineCfg, files)
+	if err != nil {
+		log.Printf("Error al ejecutar el pipeline de validators: %v", err)
+		return
+	}
+	if len(result.Diagnostics) > 0 {
+		log.Printf("Validators:\n%s", formatDiagnostics(result.Diagnostics))
+		recordValidatorDiagnostics(result.Diagnostics)
+	}
+	if result.Blocked {
+		// No tocamos state.changed: los archivos siguen pendientes y el
+		// siguiente evento reprogramará el commit una vez corregidos, sin
+		// perder lo ya detectado. El max-wait ya se detuvo más arriba, así que
+		// no reintenta por sí solo sobre archivos que siguen rotos.
+		if err := writePipelineReport(reportPath, result); err != nil {
+			log.Printf("No se pudo escribir el reporte de validators: %v", err)
+		} else {
+			log.Printf("Commit bloqueado por validators, reporte en %s", reportPath)
+		}
+		return
+	}
+
+	state.mu.Lock()
+	for _, f := range files {
+		delete(state.changed, f)
+	}
+	metricPendingChanges.Set(float64(len(state.changed)))
+	state.mu.Unlock()
+
+	start := time.Now()
 	if err := gitAddCommitPush(repoDir, files); err != nil {
 		log.Printf("Git error: %v", err)
 	} else {
-		log.Printf("Commit y push completado en %.2fs", time.Since(start).Seconds())
+		elapsed := time.Since(start)
+		metricCommitDuration.Observe(elapsed.Seconds())
+		metricLastCommitTimestamp.Set(float64(time.Now().Unix()))
+		log.Printf("Commit y push completado en %.2fs", elapsed.Seconds())
 	}
 }
 
@@ -233,26 +430,6 @@ func parseExtensions(input string) map[string]bool {
 	return exts
 }
 
-func addRecursive(w *fsnotify.Watcher, root string) error {
-	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if info.IsDir() {
-			base := filepath.Base(path)
-			// Ignorar directorios comunes que no necesitan vigilancia
-			if base == ".git" || base == "node_modules" || base == "logs" ||
-				base == "tmp" || base == "temp" {
-				return filepath.SkipDir
-			}
-			if err := w.Add(path); err != nil && verbose {
-				log.Printf("No se pudo añadir watcher a %s: %v", path, err)
-			}
-		}
-		return nil
-	})
-}
-
 func isGitRepo(repo string) bool {
 	gitDir := filepath.Join(repo, ".git")
 	info, err := os.Stat(gitDir)
@@ -287,24 +464,45 @@ func gitAddCommitPush(repo string, files []string) error {
 	}
 
 	msg := fmt.Sprintf("Auto-commit: %s [%s]", fileList, time.Now().Format("2006-01-02 15:04:05"))
-	if out, err := runGit(repo, "commit", "-m", msg); err != nil {
+	commitArgs := []string{"commit", "-m", msg}
+	if signCommit {
+		commitArgs = append(commitArgs, "-S")
+	}
+	if signOff {
+		commitArgs = append(commitArgs, "--signoff")
+	}
+	if authorName != "" || authorEmail != "" {
+		commitArgs = append(commitArgs, fmt.Sprintf("--author=%s <%s>", authorName, authorEmail))
+	}
+
+	var commitEnv []string
+	if committerName != "" {
+		commitEnv = append(commitEnv, "GIT_COMMITTER_NAME="+committerName)
+	}
+	if committerEmail != "" {
+		commitEnv = append(commitEnv, "GIT_COMMITTER_EMAIL="+committerEmail)
+	}
+
+	if out, err := runGitEnv(repo, commitEnv, commitArgs...); err != nil {
 		if !isNoChanges(out) {
 			return fmt.Errorf("git commit falló: %v -> %s", err, out)
 		}
 		return nil
 	}
 
-	// Push
-	if out, err := runGit(repo, "push"); err != nil {
-		return fmt.Errorf("git push falló: %v -> %s", err, out)
-	}
-
-	return nil
+	return pushWithRetry(repo, remoteName)
 }
 
 func runGit(repo string, args ...string) (string, error) {
+	return runGitEnv(repo, nil, args...)
+}
+
+func runGitEnv(repo string, extraEnv []string, args ...string) (string, error) {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = repo
+	if len(extraEnv) > 0 {
+		cmd.Env = append(os.Environ(), extraEnv...)
+	}
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
@@ -0,0 +1,46 @@
+// ================================================
+// MÉTRICAS PROMETHEUS – expuestas por el servidor HTTP de control
+// ================================================
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	metricPendingChanges = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "asp_monitor_pending_changes",
+		Help: "Número de archivos actualmente acumulados a la espera de un commit.",
+	})
+
+	metricLastCommitTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "asp_monitor_last_commit_timestamp_seconds",
+		Help: "Unix timestamp del último commit realizado con éxito.",
+	})
+
+	metricCommitDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "asp_monitor_commit_duration_seconds",
+		Help:    "Duración de git add + commit + push por commit.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	metricValidatorFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "asp_monitor_validator_failures_total",
+		Help: "Diagnostics producidos por el pipeline de validators, por regla.",
+	}, []string{"rule"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricPendingChanges,
+		metricLastCommitTimestamp,
+		metricCommitDuration,
+		metricValidatorFailures,
+	)
+}
+
+// recordValidatorDiagnostics vuelca los diagnostics de una ejecución del
+// pipeline en el contador asp_monitor_validator_failures_total.
+func recordValidatorDiagnostics(diags []Diagnostic) {
+	for _, d := range diags {
+		metricValidatorFailures.WithLabelValues(d.Rule).Inc()
+	}
+}
@@ -0,0 +1,176 @@
+// ================================================
+// PIPELINE DE PRE-COMMIT – ejecución de validators
+// ================================================
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultPipelineConfigPath es el nombre de archivo buscado en el directorio
+// del repo cuando no se indica --validators-config explícitamente.
+const defaultPipelineConfigPath = ".asp-validators.yaml"
+
+// PipelineConfig describe qué validators ejecutar, qué severidades bloquean
+// el commit y qué patrones ignorar por regla (inspirado en lefthook.yml).
+type PipelineConfig struct {
+	Validators      []string            `yaml:"validators"`
+	BlockSeverities []string            `yaml:"block_severities"`
+	Ignore          map[string][]string `yaml:"ignore"`
+}
+
+// defaultPipelineConfig se usa cuando no existe archivo de configuración:
+// ejecuta todos los validators conocidos y bloquea solo en "error".
+func defaultPipelineConfig() *PipelineConfig {
+	return &PipelineConfig{
+		Validators:      ValidatorNames(),
+		BlockSeverities: []string{"error"},
+	}
+}
+
+// LoadPipelineConfig carga la configuración YAML del path indicado. Si el
+// archivo no existe, devuelve defaultPipelineConfig sin error.
+func LoadPipelineConfig(path string) (*PipelineConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultPipelineConfig(), nil
+		}
+		return nil, fmt.Errorf("no se pudo leer %s: %w", path, err)
+	}
+
+	var cfg PipelineConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("config inválida en %s: %w", path, err)
+	}
+	if len(cfg.Validators) == 0 {
+		cfg.Validators = ValidatorNames()
+	}
+	if len(cfg.BlockSeverities) == 0 {
+		cfg.BlockSeverities = []string{"error"}
+	}
+	return &cfg, nil
+}
+
+// blockSeveritySet precalcula el conjunto de severidades que bloquean el commit.
+func (c *PipelineConfig) blockSeveritySet() (map[Severity]bool, error) {
+	set := make(map[Severity]bool, len(c.BlockSeverities))
+	for _, s := range c.BlockSeverities {
+		sev, err := ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		set[sev] = true
+	}
+	return set, nil
+}
+
+// ignored comprueba si el diagnóstico de una regla debe descartarse por
+// coincidir con uno de los patrones glob configurados para esa regla.
+func (c *PipelineConfig) ignored(d Diagnostic) bool {
+	for _, pattern := range c.Ignore[d.Rule] {
+		if ok, _ := filepath.Match(pattern, d.File); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(d.File)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PipelineResult agrupa los diagnostics producidos por una ejecución del
+// pipeline y si el commit debe bloquearse.
+type PipelineResult struct {
+	Diagnostics []Diagnostic
+	Blocked     bool
+}
+
+// RunPipeline ejecuta, en orden, cada validator listado en cfg.Validators
+// sobre files, filtra los diagnostics ignorados y decide si el resultado
+// bloquea el commit según BlockSeverities.
+func RunPipeline(cfg *PipelineConfig, files []string) (*PipelineResult, error) {
+	blockSet, err := cfg.blockSeveritySet()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PipelineResult{}
+	for _, name := range cfg.Validators {
+		v, ok := GetValidator(name)
+		if !ok {
+			return nil, fmt.Errorf("validator desconocido en config: %q", name)
+		}
+
+		for _, d := range v.Validate(files) {
+			if cfg.ignored(d) {
+				continue
+			}
+			result.Diagnostics = append(result.Diagnostics, d)
+			if blockSet[d.Severity] {
+				result.Blocked = true
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// reportDiagnostic es la forma serializable de un Diagnostic en el reporte JSON.
+type reportDiagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// pipelineReport es el documento escrito a disco cuando un commit se bloquea,
+// para que un humano (o tooling externo) pueda inspeccionar qué falló sin
+// tener que revisar los logs del agente.
+type pipelineReport struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Blocked     bool               `json:"blocked"`
+	Diagnostics []reportDiagnostic `json:"diagnostics"`
+}
+
+// writePipelineReport serializa result como JSON en path.
+func writePipelineReport(path string, result *PipelineResult) error {
+	report := pipelineReport{
+		GeneratedAt: time.Now(),
+		Blocked:     result.Blocked,
+	}
+	for _, d := range result.Diagnostics {
+		report.Diagnostics = append(report.Diagnostics, reportDiagnostic{
+			File: d.File, Line: d.Line, Column: d.Column,
+			Severity: d.Severity.String(), Rule: d.Rule, Message: d.Message,
+		})
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("no se pudo generar el reporte: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("no se pudo escribir el reporte en %s: %w", path, err)
+	}
+	return nil
+}
+
+// formatDiagnostics concatena los diagnostics en líneas de log, una por hallazgo.
+func formatDiagnostics(diags []Diagnostic) string {
+	lines := make([]string, len(diags))
+	for i, d := range diags {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -6,40 +6,198 @@ package main
 import (
 	"fmt"
 	"io/ioutil"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/ibai-mutiloa/asp-file-monitor-go/agent/aspparse"
 )
 
+// Severity indica el nivel de gravedad de un Diagnostic.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarn
+	SeverityError
+)
+
+// String devuelve el nombre legible de la severidad (usado en config y reportes).
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSeverity convierte el nombre de una severidad (tal y como aparece en la
+// config) a su valor Severity. Devuelve error si no se reconoce.
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "info":
+		return SeverityInfo, nil
+	case "warn", "warning":
+		return SeverityWarn, nil
+	case "error":
+		return SeverityError, nil
+	default:
+		return SeverityInfo, fmt.Errorf("severidad desconocida: %q", s)
+	}
+}
+
+// Diagnostic representa un hallazgo de un Validator sobre un archivo concreto.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+// String formatea el diagnostic como una línea de log legible:
+// [RULE] archivo:línea:columna severidad → mensaje
+func (d Diagnostic) String() string {
+	loc := d.File
+	if d.Line > 0 {
+		if d.Column > 0 {
+			loc = fmt.Sprintf("%s:%d:%d", d.File, d.Line, d.Column)
+		} else {
+			loc = fmt.Sprintf("%s:%d", d.File, d.Line)
+		}
+	}
+	return fmt.Sprintf("[%s] %s %s → %s", strings.ToUpper(d.Rule), loc, d.Severity, d.Message)
+}
+
+// Validator es la interfaz que implementa cada comprobación estática. Cada
+// Validator se registra una única vez (ver RegisterValidator) y se ejecuta
+// como una etapa del pipeline de pre-commit.
+type Validator interface {
+	// Name identifica al validator en la configuración (pipeline.validators).
+	Name() string
+	// Validate analiza los archivos indicados y devuelve sus diagnostics.
+	// Solo debe considerar los archivos relevantes para el validator (p.ej.
+	// los que terminan en .asp); el resto se ignoran silenciosamente.
+	Validate(files []string) []Diagnostic
+}
+
+// registry mantiene los validators disponibles, indexados por nombre.
+var registry = map[string]Validator{}
+
+// RegisterValidator añade un Validator al registro global. Se llama desde los
+// init() de cada validator concreto.
+func RegisterValidator(v Validator) {
+	registry[v.Name()] = v
+}
+
+// GetValidator busca un validator registrado por nombre.
+func GetValidator(name string) (Validator, bool) {
+	v, ok := registry[name]
+	return v, ok
+}
+
+// ValidatorNames devuelve los nombres de todos los validators registrados.
+func ValidatorNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	return names
+}
+
+func init() {
+	RegisterValidator(&includeValidator{})
+	RegisterValidator(&blockBalanceValidator{})
+	RegisterValidator(&unicodeQuotesValidator{})
+	RegisterValidator(&cscriptValidator{})
+}
+
 var includeRegex = regexp.MustCompile(`<!--#include (file|virtual)="([^"]+)"-->`)
-var ifRegex = regexp.MustCompile(`(?i)\bif\b`)
-var endIfRegex = regexp.MustCompile(`(?i)\bend if\b`)
-var forRegex = regexp.MustCompile(`(?i)\bfor\b`)
-var nextRegex = regexp.MustCompile(`(?i)\bnext\b`)
 
-func ValidateASPWithCScript(files []string) []string {
-	var errors []string
+// lineAt devuelve el número de línea (1-indexado) del offset dado dentro de text.
+func lineAt(text string, offset int) int {
+	if offset < 0 {
+		return 0
+	}
+	return strings.Count(text[:offset], "\n") + 1
+}
+
+// includeValidator sigue recursivamente el grafo de <!--#include file=...-->
+// y <!--#include virtual=...--> de cada archivo, resolviendo las rutas
+// "virtual=" contra los --vroot configurados, y reporta includes rotos,
+// ciclos e inclusiones duplicadas dentro de una misma página.
+type includeValidator struct{}
+
+func (v *includeValidator) Name() string { return "include" }
+
+func (v *includeValidator) Validate(files []string) []Diagnostic {
+	var diags []Diagnostic
 
 	for _, file := range files {
 		if strings.ToLower(filepath.Ext(file)) != ".asp" {
 			continue
 		}
 
-		cmd := exec.Command("cscript.exe", "//nologo", file)
-		output, err := cmd.CombinedOutput()
+		root := filepath.Clean(file)
+		diags = append(diags, walkIncludes(root, vroots, repoDir, []string{root}, map[string]bool{root: true}, make(map[string]int), nil)...)
+	}
+
+	return diags
+}
+
+// blockBalanceValidator comprueba que los bloques de VBScript (If/End If,
+// For/Next, Do/Loop, While/Wend, Select Case/End Select, Sub|Function/End
+// Sub|Function) estén correctamente anidados. A diferencia del conteo de
+// palabras clave original, usa el tokenizador de aspparse, por lo que no se
+// confunde con cadenas, comentarios ni HTML.
+type blockBalanceValidator struct{}
+
+func (v *blockBalanceValidator) Name() string { return "block-balance" }
+
+func (v *blockBalanceValidator) Validate(files []string) []Diagnostic {
+	var diags []Diagnostic
 
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file)) != ".asp" {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(file)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("[CSCRIPT] %s → %v\n%s", file, err, string(output)))
+			diags = append(diags, Diagnostic{
+				File: file, Rule: "block-balance", Severity: SeverityError,
+				Message: fmt.Sprintf("no se pudo leer el archivo: %v", err),
+			})
+			continue
+		}
+
+		tokens := aspparse.Tokenize(string(content))
+		for _, d := range aspparse.CheckBlocks(tokens) {
+			diags = append(diags, Diagnostic{
+				File: file, Line: d.Line, Rule: "block-balance", Severity: SeverityError,
+				Message: d.Message,
+			})
 		}
 	}
 
-	return errors
+	return diags
 }
 
-func ValidateASPFiles(files []string) []string {
-	var errors []string
+// unicodeQuotesValidator detecta comillas tipográficas (“ ”) que VBScript no
+// interpreta como delimitadores de cadena válidos.
+type unicodeQuotesValidator struct{}
+
+func (v *unicodeQuotesValidator) Name() string { return "unicode-quotes" }
+
+func (v *unicodeQuotesValidator) Validate(files []string) []Diagnostic {
+	var diags []Diagnostic
 
 	for _, file := range files {
 		if strings.ToLower(filepath.Ext(file)) != ".asp" {
@@ -48,46 +206,45 @@ func ValidateASPFiles(files []string) []string {
 
 		content, err := ioutil.ReadFile(file)
 		if err != nil {
-			errors = append(errors, fmt.Sprintf("[ERROR] No se pudo leer %s: %v", file, err))
 			continue
 		}
-
 		text := string(content)
 
-		// 1️⃣ Validar includes rotos
-		matches := includeRegex.FindAllStringSubmatch(text, -1)
-		for _, m := range matches {
-			path := m[2]
-
-			// Resolver ruta relativa
-			resolved := filepath.Join(filepath.Dir(file), path)
-			if _, err := os.Stat(resolved); err != nil {
-				errors = append(errors,
-					fmt.Sprintf("[INCLUDE] En %s → archivo no encontrado: %s", file, path))
-			}
+		if strings.ContainsAny(text, "“”") {
+			diags = append(diags, Diagnostic{
+				File: file, Rule: "unicode-quotes", Severity: SeverityWarn,
+				Message: "comillas tipográficas inválidas detectadas",
+			})
 		}
+	}
 
-		// 2️⃣ Validar If / End If balanceados
-		ifCount := len(ifRegex.FindAllString(text, -1))
-		endifCount := len(endIfRegex.FindAllString(text, -1))
-		if ifCount != endifCount {
-			errors = append(errors,
-				fmt.Sprintf("[SINTAXIS] En %s → IF (%d) y END IF (%d) no coinciden", file, ifCount, endifCount))
-		}
+	return diags
+}
+
+// cscriptValidator ejecuta el archivo con el intérprete VBScript de Windows
+// (cscript.exe) para detectar errores de ejecución reales. Solo es útil en
+// entornos Windows con IIS/cscript disponible.
+type cscriptValidator struct{}
 
-		// 3️⃣ Validar For / Next balanceados
-		forCount := len(forRegex.FindAllString(text, -1))
-		nextCount := len(nextRegex.FindAllString(text, -1))
-		if forCount != nextCount {
-			errors = append(errors,
-				fmt.Sprintf("[SINTAXIS] En %s → FOR (%d) y NEXT (%d) no coinciden", file, forCount, nextCount))
+func (v *cscriptValidator) Name() string { return "cscript" }
+
+func (v *cscriptValidator) Validate(files []string) []Diagnostic {
+	var diags []Diagnostic
+
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file)) != ".asp" {
+			continue
 		}
 
-		// 4️⃣ Detectar comillas curvas no válidas
-		if strings.Contains(text, "“") || strings.Contains(text, "”") {
-			errors = append(errors, fmt.Sprintf("[UNICODE] En %s → comillas inválidas detectadas", file))
+		cmd := exec.Command("cscript.exe", "//nologo", file)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				File: file, Rule: "cscript", Severity: SeverityError,
+				Message: fmt.Sprintf("%v\n%s", err, string(output)),
+			})
 		}
 	}
 
-	return errors
+	return diags
 }
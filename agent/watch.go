@@ -0,0 +1,242 @@
+// ================================================
+// WATCHER – reconciliación periódica y fallback de polling
+// ================================================
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// maxUserWatchesPath expone el límite de watches de inotify configurado en
+// el kernel, usado únicamente para dar una pista accionable en los logs.
+const maxUserWatchesPath = "/proc/sys/fs/inotify/max_user_watches"
+
+// isSkippedDir indica si un directorio (por su nombre base) no necesita
+// vigilarse nunca, tanto para el watcher como para --print-includes.
+func isSkippedDir(base string) bool {
+	return base == ".git" || base == "node_modules" || base == "logs" ||
+		base == "tmp" || base == "temp"
+}
+
+// watchManager añade watchers de fsnotify de forma recursiva y, a
+// diferencia de la versión original, no descarta los fallos: reconcile()
+// los reintenta periódicamente, y los directorios que sigan sin poder
+// vigilarse vía inotify caen a un fallback de polling.
+type watchManager struct {
+	watcher      *fsnotify.Watcher
+	root         string
+	pollInterval time.Duration
+
+	mu         sync.Mutex
+	watched    map[string]bool
+	failedDirs map[string]bool
+	pollSeen   map[string]time.Time
+
+	pollEvents chan fsnotify.Event
+}
+
+func newWatchManager(watcher *fsnotify.Watcher, root string, pollInterval time.Duration) *watchManager {
+	return &watchManager{
+		watcher:      watcher,
+		root:         root,
+		pollInterval: pollInterval,
+		watched:      make(map[string]bool),
+		failedDirs:   make(map[string]bool),
+		pollSeen:     make(map[string]time.Time),
+		pollEvents:   make(chan fsnotify.Event, 64),
+	}
+}
+
+// Events devuelve el canal de eventos sintéticos generados por el fallback
+// de polling; se consume igual que watcher.Events.
+func (m *watchManager) Events() <-chan fsnotify.Event {
+	return m.pollEvents
+}
+
+// addRecursive añade watchers a root y a todos sus subdirectorios (salvo los
+// ignorados). Los directorios en los que falla w.Add quedan registrados
+// para reintento y polling en vez de perderse silenciosamente.
+func (m *watchManager) addRecursive(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if isSkippedDir(filepath.Base(path)) {
+			return filepath.SkipDir
+		}
+		m.addDir(path)
+		return nil
+	})
+}
+
+// addDir intenta añadir un único directorio al watcher nativo.
+func (m *watchManager) addDir(path string) {
+	if err := m.watcher.Add(path); err != nil {
+		m.mu.Lock()
+		wasFailed := m.failedDirs[path]
+		m.failedDirs[path] = true
+		m.mu.Unlock()
+		if !wasFailed {
+			logWatchFailure(path, err)
+		}
+		return
+	}
+
+	m.mu.Lock()
+	m.watched[path] = true
+	delete(m.failedDirs, path)
+	m.mu.Unlock()
+}
+
+// reAddAfterAtomicSave vuelve a intentar vigilar path (y su directorio padre, por si el
+// watch que se perdió era el del contenedor) tras un Remove/Rename cuyo
+// origen sigue existiendo en disco — el patrón típico de un "atomic save"
+// de editor (escribir a un archivo temporal y renombrarlo sobre el
+// original), que en Linux puede dejar el watch de inotify huérfano.
+func (m *watchManager) reAddAfterAtomicSave(path string) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		m.addDir(path)
+	}
+	parent := filepath.Dir(path)
+	if info, err := os.Stat(parent); err == nil && info.IsDir() {
+		m.addDir(parent)
+	}
+}
+
+// reconcile vuelve a recorrer root: añade directorios nuevos o que antes
+// fallaron, y olvida los que ya no existen en disco.
+func (m *watchManager) reconcile() {
+	seen := make(map[string]bool)
+
+	_ = filepath.Walk(m.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if isSkippedDir(filepath.Base(path)) {
+			return filepath.SkipDir
+		}
+		seen[path] = true
+
+		m.mu.Lock()
+		_, isWatched := m.watched[path]
+		_, hadFailed := m.failedDirs[path]
+		m.mu.Unlock()
+
+		if !isWatched || hadFailed {
+			m.addDir(path)
+		}
+		return nil
+	})
+
+	m.mu.Lock()
+	for dir := range m.watched {
+		if !seen[dir] {
+			delete(m.watched, dir)
+			_ = m.watcher.Remove(dir)
+		}
+	}
+	for dir := range m.failedDirs {
+		if !seen[dir] {
+			delete(m.failedDirs, dir)
+		}
+	}
+	for path := range m.pollSeen {
+		if !seen[filepath.Dir(path)] {
+			delete(m.pollSeen, path)
+		}
+	}
+	m.mu.Unlock()
+}
+
+// pollFailedDirs escanea los directorios que no se pudieron vigilar vía
+// inotify y emite un evento sintético de Write por cada archivo nuevo o
+// modificado desde el último escaneo.
+func (m *watchManager) pollFailedDirs() {
+	m.mu.Lock()
+	dirs := make([]string, 0, len(m.failedDirs))
+	for d := range m.failedDirs {
+		dirs = append(dirs, d)
+	}
+	m.mu.Unlock()
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+
+			m.mu.Lock()
+			last, known := m.pollSeen[path]
+			m.pollSeen[path] = info.ModTime()
+			m.mu.Unlock()
+
+			if !known || info.ModTime().After(last) {
+				select {
+				case m.pollEvents <- fsnotify.Event{Name: path, Op: fsnotify.Write}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// maintain ejecuta reconcile() y pollFailedDirs() en cada tick hasta que
+// stop se cierra. Se lanza como goroutine propia.
+func (m *watchManager) maintain(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.reconcile()
+			m.pollFailedDirs()
+		}
+	}
+}
+
+// logWatchFailure registra el fallo al añadir un watch y, si corresponde a
+// un agotamiento de watches/descriptores de inotify en Linux, añade una
+// sugerencia accionable con el límite actual del kernel.
+func logWatchFailure(path string, err error) {
+	low := strings.ToLower(err.Error())
+	exhausted := strings.Contains(low, "no space left on device") ||
+		strings.Contains(low, "too many open files")
+
+	if !exhausted {
+		log.Printf("No se pudo añadir watcher a %s: %v", path, err)
+		return
+	}
+
+	log.Printf("No se pudo añadir watcher a %s: %v (límite de inotify alcanzado, usando polling)", path, err)
+	if runtime.GOOS == "linux" {
+		if limit, rerr := os.ReadFile(maxUserWatchesPath); rerr == nil {
+			log.Printf("fs.inotify.max_user_watches actual: %s — auméntalo con: sudo sysctl fs.inotify.max_user_watches=<valor mayor>", strings.TrimSpace(string(limit)))
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// countDirs recorre root y cuenta los directorios no ignorados, incluido
+// root, igual que haría watchManager.
+func countDirs(t *testing.T, root string) int {
+	t.Helper()
+	count := 0
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if isSkippedDir(filepath.Base(path)) {
+				return filepath.SkipDir
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error al recorrer %s: %v", root, err)
+	}
+	return count
+}
+
+func newTestWatchManager(t *testing.T, root string) *watchManager {
+	t.Helper()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("no se pudo crear el watcher: %v", err)
+	}
+	t.Cleanup(func() { watcher.Close() })
+	return newWatchManager(watcher, root, time.Hour)
+}
+
+// TestReconcilePicksUpManyNewDirs stresa el reconciliador creando muchos
+// directorios y archivos tras el escaneo inicial, al estilo del patrón de
+// pruebas de fsnotify de crear árboles grandes para comprobar que no se
+// pierde ningún watch.
+func TestReconcilePicksUpManyNewDirs(t *testing.T) {
+	root := t.TempDir()
+	wm := newTestWatchManager(t, root)
+
+	if err := wm.addRecursive(root); err != nil {
+		t.Fatalf("addRecursive falló: %v", err)
+	}
+
+	const dirCount = 50
+	for i := 0; i < dirCount; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("sub-%03d", i))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("no se pudo crear %s: %v", dir, err)
+		}
+		file := filepath.Join(dir, "page.asp")
+		if err := os.WriteFile(file, []byte("<% Response.Write \"hi\" %>"), 0644); err != nil {
+			t.Fatalf("no se pudo crear %s: %v", file, err)
+		}
+	}
+
+	wm.reconcile()
+
+	want := countDirs(t, root)
+	wm.mu.Lock()
+	got := len(wm.watched)
+	wm.mu.Unlock()
+
+	if got != want {
+		t.Errorf("esperaba %d directorios vigilados tras reconcile, obtuve %d", want, got)
+	}
+}
+
+// TestReconcileForgetsRemovedDirs comprueba que, tras borrar un directorio
+// vigilado, reconcile() deja de considerarlo vigilado.
+func TestReconcileForgetsRemovedDirs(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "borrame")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("no se pudo crear %s: %v", sub, err)
+	}
+
+	wm := newTestWatchManager(t, root)
+	if err := wm.addRecursive(root); err != nil {
+		t.Fatalf("addRecursive falló: %v", err)
+	}
+
+	wm.mu.Lock()
+	_, watchedBefore := wm.watched[sub]
+	wm.mu.Unlock()
+	if !watchedBefore {
+		t.Fatalf("%s debería estar vigilado tras addRecursive", sub)
+	}
+
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatalf("no se pudo borrar %s: %v", sub, err)
+	}
+	wm.reconcile()
+
+	wm.mu.Lock()
+	_, watchedAfter := wm.watched[sub]
+	wm.mu.Unlock()
+	if watchedAfter {
+		t.Errorf("%s debería haberse olvidado tras borrarse y reconciliar", sub)
+	}
+}
+
+// TestPollFailedDirsEmitsEventForNewFile simula un directorio que no pudo
+// vigilarse vía inotify (sin depender de agotar watches de verdad) y
+// comprueba que el fallback de polling detecta un archivo nuevo.
+func TestPollFailedDirsEmitsEventForNewFile(t *testing.T) {
+	root := t.TempDir()
+	wm := newTestWatchManager(t, root)
+
+	wm.mu.Lock()
+	wm.failedDirs[root] = true
+	wm.mu.Unlock()
+
+	file := filepath.Join(root, "a.asp")
+	if err := os.WriteFile(file, []byte("<% %>"), 0644); err != nil {
+		t.Fatalf("no se pudo crear %s: %v", file, err)
+	}
+
+	wm.pollFailedDirs()
+
+	select {
+	case ev := <-wm.Events():
+		if ev.Name != file {
+			t.Errorf("esperaba evento para %s, obtuve %s", file, ev.Name)
+		}
+	default:
+		t.Fatal("esperaba un evento sintético de polling para el archivo nuevo")
+	}
+}